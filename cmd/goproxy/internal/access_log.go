@@ -0,0 +1,268 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Supported values for the --access-log-format flag.
+const (
+	accessLogFormatCombined = "combined"
+	accessLogFormatCommon   = "common"
+	accessLogFormatJSON     = "json"
+)
+
+// accessLogFields are the structured fields that can be selected via
+// --access-log-fields for the json access log format.
+var accessLogFields = []string{"module", "version", "op", "sumdb", "cache", "latency", "bytes"}
+
+// accessLogger writes one access log line per request in the configured
+// format, and supports reopening its underlying file for log rotation.
+type accessLogger struct {
+	path   string
+	format string
+	fields map[string]bool
+
+	mu   sync.Mutex
+	file *os.File
+}
+
+// newAccessLogger creates an [accessLogger] that appends to path in format,
+// including only the given fields (all of [accessLogFields] if empty) in
+// json mode.
+func newAccessLogger(path, format string, fields []string) (*accessLogger, error) {
+	l := &accessLogger{path: path, format: format, fields: accessLogFieldSet(fields)}
+	if err := l.Reopen(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// accessLogFieldSet builds a lookup set from fields, defaulting to all of
+// [accessLogFields] when fields is empty.
+func accessLogFieldSet(fields []string) map[string]bool {
+	if len(fields) == 0 {
+		fields = accessLogFields
+	}
+	set := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		set[f] = true
+	}
+	return set
+}
+
+// Reopen closes and reopens the log file, for use after external log
+// rotation (e.g. in response to SIGUSR1).
+func (l *accessLogger) Reopen() error {
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("open access log %s: %w", l.path, err)
+	}
+	l.mu.Lock()
+	old := l.file
+	l.file = f
+	l.mu.Unlock()
+	if old != nil {
+		_ = old.Close()
+	}
+	return nil
+}
+
+// Close closes the log file.
+func (l *accessLogger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	return l.file.Close()
+}
+
+// log formats and appends entry to the log file.
+func (l *accessLogger) log(entry accessLogEntry) {
+	var line string
+	switch l.format {
+	case accessLogFormatJSON:
+		line = entry.formatJSON(l.fields)
+	case accessLogFormatCommon:
+		line = entry.formatCommon()
+	default:
+		line = entry.formatCombined()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file != nil {
+		fmt.Fprintln(l.file, line)
+	}
+}
+
+// accessLogEntry describes a single completed request.
+type accessLogEntry struct {
+	remoteAddr string
+	startTime  time.Time
+	method     string
+	requestURI string
+	proto      string
+	referer    string
+	userAgent  string
+	status     int
+	respBytes  int64
+	latency    time.Duration
+
+	module  string
+	version string
+	op      string
+	sumdb   string
+	cache   string // "hit", "miss", or "" if unknown
+}
+
+// formatCommon renders entry using the Apache/NCSA "common" log format.
+func (e accessLogEntry) formatCommon() string {
+	return fmt.Sprintf("%s - - [%s] %q %d %d",
+		e.remoteAddr, e.startTime.Format("02/Jan/2006:15:04:05 -0700"),
+		fmt.Sprintf("%s %s %s", e.method, e.requestURI, e.proto), e.status, e.respBytes)
+}
+
+// formatCombined renders entry using the Apache/NCSA "combined" log format.
+func (e accessLogEntry) formatCombined() string {
+	return fmt.Sprintf("%s %q %q", e.formatCommon(), e.referer, e.userAgent)
+}
+
+// formatJSON renders entry as a single-line JSON object, including only the
+// goproxy-specific fields selected in fields.
+func (e accessLogEntry) formatJSON(fields map[string]bool) string {
+	m := map[string]any{
+		"time":       e.startTime.Format(time.RFC3339),
+		"remoteAddr": e.remoteAddr,
+		"method":     e.method,
+		"requestURI": e.requestURI,
+		"proto":      e.proto,
+		"referer":    e.referer,
+		"userAgent":  e.userAgent,
+		"status":     e.status,
+	}
+	if fields["bytes"] {
+		m["bytes"] = e.respBytes
+	}
+	if fields["latency"] {
+		m["latencyMs"] = float64(e.latency) / float64(time.Millisecond)
+	}
+	if fields["module"] && e.module != "" {
+		m["module"] = e.module
+	}
+	if fields["version"] && e.version != "" {
+		m["version"] = e.version
+	}
+	if fields["op"] && e.op != "" {
+		m["op"] = e.op
+	}
+	if fields["sumdb"] && e.sumdb != "" {
+		m["sumdb"] = e.sumdb
+	}
+	if fields["cache"] && e.cache != "" {
+		m["cache"] = e.cache
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Sprintf(`{"error":%q}`, err.Error())
+	}
+	return string(b)
+}
+
+// accessLogResponseWriter wraps an [http.ResponseWriter] to capture the
+// status code and number of bytes written for access logging.
+type accessLogResponseWriter struct {
+	http.ResponseWriter
+	status      int
+	bytes       int64
+	wroteHeader bool
+}
+
+// WriteHeader implements [http.ResponseWriter].
+func (w *accessLogResponseWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write implements [http.ResponseWriter].
+func (w *accessLogResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// accessLogMiddleware wraps next with an [accessLogger] that records one
+// entry per request, enriched with the module, version, op, and sumdb name
+// parsed from goproxy's `@v/...` and `/sumdb/...` URL conventions, plus the
+// cache hit/miss outcome recorded via [withCacheStats].
+func accessLogMiddleware(next http.Handler, logger *accessLogger) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+		stats := &cacheStats{}
+		req = req.WithContext(withCacheStats(req.Context(), stats))
+		lrw := &accessLogResponseWriter{ResponseWriter: rw}
+		next.ServeHTTP(lrw, req)
+
+		module, version, op, sumdb := parseModuleRequestPath(req.URL.Path)
+		logger.log(accessLogEntry{
+			remoteAddr: req.RemoteAddr,
+			startTime:  start,
+			method:     req.Method,
+			requestURI: req.RequestURI,
+			proto:      req.Proto,
+			referer:    req.Referer(),
+			userAgent:  req.UserAgent(),
+			status:     lrw.status,
+			respBytes:  lrw.bytes,
+			latency:    time.Since(start),
+			module:     module,
+			version:    version,
+			op:         op,
+			sumdb:      sumdb,
+			cache:      stats.result(),
+		})
+	})
+}
+
+// parseModuleRequestPath parses the module path, version, and operation
+// (`list`, `latest`, `info`, `mod`, `zip`, `ziphash`) out of a goproxy
+// module proxy request path, or the checksum database name out of a
+// `/sumdb/<name>/...` request path.
+func parseModuleRequestPath(p string) (module, version, op, sumdb string) {
+	p = strings.TrimPrefix(p, "/")
+
+	if rest, ok := strings.CutPrefix(p, "sumdb/"); ok {
+		name, _, _ := strings.Cut(rest, "/")
+		return "", "", "", name
+	}
+
+	if module, ok := strings.CutSuffix(p, "/@latest"); ok {
+		return module, "", "latest", ""
+	}
+
+	modulePath, tail, ok := strings.Cut(p, "/@v/")
+	if !ok {
+		return "", "", "", ""
+	}
+	if tail == "list" {
+		return modulePath, "", "list", ""
+	}
+
+	dot := strings.LastIndexByte(tail, '.')
+	if dot == -1 {
+		return modulePath, "", "", ""
+	}
+	return modulePath, tail[:dot], tail[dot+1:], ""
+}
@@ -0,0 +1,144 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/goproxy/goproxy"
+)
+
+// Supported values for the --cache-backend flag.
+const (
+	cacheBackendDir   = "dir"
+	cacheBackendS3    = "s3"
+	cacheBackendGCS   = "gcs"
+	cacheBackendRedis = "redis"
+)
+
+// newCacher builds the [goproxy.Cacher] selected by cfg.cacheBackend. For
+// the remote backends (s3, gcs, redis), the returned cacher is backed by a
+// small local disk tier (see [tieredCacher]) so repeatedly requested
+// objects don't round-trip to the remote store on every request.
+func newCacher(ctx context.Context, cfg *serverCmdConfig) (goproxy.Cacher, error) {
+	switch cfg.cacheBackend {
+	case "", cacheBackendDir:
+		return goproxy.DirCacher(cfg.cacheDir), nil
+	case cacheBackendS3:
+		remote, err := newS3Cacher(ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+		return newTieredCacher(cfg, remote), nil
+	case cacheBackendGCS:
+		remote, err := newGCSCacher(ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+		return newTieredCacher(cfg, remote), nil
+	case cacheBackendRedis:
+		remote, err := newRedisCacher(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return newTieredCacher(cfg, remote), nil
+	default:
+		return nil, fmt.Errorf("unknown cache backend %q", cfg.cacheBackend)
+	}
+}
+
+// tieredCacher serves cache hits from a local [goproxy.DirCacher] before
+// falling through to a remote [goproxy.Cacher], writing remote hits and all
+// puts back to the local tier so a single replica doesn't keep re-fetching
+// hot objects from the remote store.
+type tieredCacher struct {
+	local  goproxy.Cacher
+	remote goproxy.Cacher
+}
+
+// newTieredCacher creates a [tieredCacher] fronting remote with a local
+// directory cache rooted at the goproxy-hot-cache directory under
+// cfg.tempDir.
+func newTieredCacher(cfg *serverCmdConfig, remote goproxy.Cacher) *tieredCacher {
+	return &tieredCacher{
+		local:  goproxy.DirCacher(filepath.Join(cfg.tempDir, "goproxy-hot-cache")),
+		remote: remote,
+	}
+}
+
+// Get implements [goproxy.Cacher].
+func (c *tieredCacher) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	if rc, err := c.local.Get(ctx, name); err == nil {
+		return rc, nil
+	}
+
+	rc, err := c.remote.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	tmp, err := os.CreateTemp("", "goproxy-hot-cache-*")
+	if err != nil {
+		// The local tier is a best-effort optimization; fall back to
+		// serving directly from the remote store.
+		return rc, nil
+	}
+	return &teeReadCloser{
+		r:    io.TeeReader(rc, tmp),
+		rc:   rc,
+		tmp:  tmp,
+		name: name,
+		put:  c.local.Put,
+	}, nil
+}
+
+// Put implements [goproxy.Cacher].
+func (c *tieredCacher) Put(ctx context.Context, name string, content io.ReadSeeker) error {
+	if err := c.remote.Put(ctx, name, content); err != nil {
+		return err
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return nil
+	}
+	return c.local.Put(ctx, name, content)
+}
+
+// teeReadCloser streams a remote cache hit through to the caller while
+// copying it to a temporary file, which is promoted into the local disk
+// tier only if the caller actually read it through to io.EOF. A caller that
+// aborts partway through (client disconnect, fetch timeout, canceled
+// context) leaves a truncated temp file, which is discarded instead of
+// corrupting the local tier's entry for name.
+type teeReadCloser struct {
+	r          io.Reader
+	rc         io.ReadCloser
+	tmp        *os.File
+	name       string
+	put        func(ctx context.Context, name string, content io.ReadSeeker) error
+	reachedEOF bool
+}
+
+// Read implements [io.Reader].
+func (t *teeReadCloser) Read(p []byte) (int, error) {
+	n, err := t.r.Read(p)
+	if err == io.EOF {
+		t.reachedEOF = true
+	}
+	return n, err
+}
+
+// Close implements [io.Closer], promoting the tee'd copy into the local
+// cache tier only if it was read in full.
+func (t *teeReadCloser) Close() error {
+	err := t.rc.Close()
+	if t.reachedEOF {
+		if _, seekErr := t.tmp.Seek(0, io.SeekStart); seekErr == nil {
+			_ = t.put(context.Background(), t.name, t.tmp)
+		}
+	}
+	_ = t.tmp.Close()
+	_ = os.Remove(t.tmp.Name())
+	return err
+}
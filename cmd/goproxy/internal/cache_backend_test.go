@@ -0,0 +1,82 @@
+package internal
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+)
+
+// memCacher is an in-memory [github.com/goproxy/goproxy.Cacher] used to
+// exercise [tieredCacher] without touching the filesystem or a real remote
+// backend.
+type memCacher struct {
+	objects map[string]string
+}
+
+func newMemCacher() *memCacher {
+	return &memCacher{objects: map[string]string{}}
+}
+
+func (c *memCacher) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	data, ok := c.objects[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "get", Path: name, Err: fs.ErrNotExist}
+	}
+	return io.NopCloser(strings.NewReader(data)), nil
+}
+
+func (c *memCacher) Put(ctx context.Context, name string, content io.ReadSeeker) error {
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return err
+	}
+	c.objects[name] = string(data)
+	return nil
+}
+
+func TestTieredCacherPromotesOnFullRead(t *testing.T) {
+	remote := newMemCacher()
+	remote.objects["mod"] = "module content"
+	local := newMemCacher()
+	c := &tieredCacher{local: local, remote: remote}
+
+	rc, err := c.Get(context.Background(), "mod")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if _, err := io.ReadAll(rc); err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if err := rc.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	if got := local.objects["mod"]; got != "module content" {
+		t.Errorf("local tier after full read = %q, want %q", got, "module content")
+	}
+}
+
+func TestTieredCacherDoesNotPromoteOnPartialRead(t *testing.T) {
+	remote := newMemCacher()
+	remote.objects["mod"] = "module content"
+	local := newMemCacher()
+	c := &tieredCacher{local: local, remote: remote}
+
+	rc, err := c.Get(context.Background(), "mod")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	buf := make([]byte, 4)
+	if _, err := rc.Read(buf); err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if err := rc.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	if _, ok := local.objects["mod"]; ok {
+		t.Errorf("local tier was populated from a partial read, want no entry")
+	}
+}
@@ -0,0 +1,57 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+
+	"cloud.google.com/go/storage"
+)
+
+// gcsCacher is a [github.com/goproxy/goproxy.Cacher] backed by a Google
+// Cloud Storage bucket. Objects are streamed in and out without buffering
+// the full module zip in memory.
+type gcsCacher struct {
+	client *storage.Client
+	bucket string
+}
+
+// newGCSCacher creates a [gcsCacher] for cfg.cacheGCSBucket, loading
+// credentials from the environment (GOOGLE_APPLICATION_CREDENTIALS or the
+// ambient metadata server credentials).
+func newGCSCacher(ctx context.Context, cfg *serverCmdConfig) (*gcsCacher, error) {
+	if cfg.cacheGCSBucket == "" {
+		return nil, fmt.Errorf("cache-gcs-bucket is required for the gcs cache backend")
+	}
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("create GCS client: %w", err)
+	}
+	return &gcsCacher{client: client, bucket: cfg.cacheGCSBucket}, nil
+}
+
+// Get implements [github.com/goproxy/goproxy.Cacher], translating a missing
+// object into fs.ErrNotExist so callers can tell a cache miss apart from a
+// hard backend failure.
+func (c *gcsCacher) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	r, err := c.client.Bucket(c.bucket).Object(name).NewReader(ctx)
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return nil, &fs.PathError{Op: "get", Path: name, Err: fs.ErrNotExist}
+		}
+		return nil, err
+	}
+	return r, nil
+}
+
+// Put implements [github.com/goproxy/goproxy.Cacher].
+func (c *gcsCacher) Put(ctx context.Context, name string, content io.ReadSeeker) error {
+	w := c.client.Bucket(c.bucket).Object(name).NewWriter(ctx)
+	if _, err := io.Copy(w, content); err != nil {
+		_ = w.Close()
+		return fmt.Errorf("write %s: %w", name, err)
+	}
+	return w.Close()
+}
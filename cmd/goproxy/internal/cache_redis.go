@@ -0,0 +1,117 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisCacheChunkSize bounds how much of a cached object is read from or
+// written to Redis at a time, so a single large module zip doesn't have to
+// be held in memory all at once.
+const redisCacheChunkSize = 1 << 20 // 1 MiB
+
+// redisCacher is a [github.com/goproxy/goproxy.Cacher] backed by Redis,
+// storing each object as a single string value keyed by its cache name.
+type redisCacher struct {
+	client *redis.Client
+}
+
+// newRedisCacher creates a [redisCacher] connected to cfg.cacheRedisAddr,
+// authenticating with REDIS_PASSWORD from the environment if set.
+func newRedisCacher(cfg *serverCmdConfig) (*redisCacher, error) {
+	if cfg.cacheRedisAddr == "" {
+		return nil, fmt.Errorf("cache-redis-addr is required for the redis cache backend")
+	}
+	return &redisCacher{client: redis.NewClient(&redis.Options{
+		Addr:     cfg.cacheRedisAddr,
+		Password: os.Getenv("REDIS_PASSWORD"),
+	})}, nil
+}
+
+// Get implements [github.com/goproxy/goproxy.Cacher], reading the value in
+// [redisCacheChunkSize] chunks via GETRANGE rather than loading it into
+// memory all at once.
+func (c *redisCacher) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	size, err := c.client.StrLen(ctx, name).Result()
+	if err != nil {
+		return nil, err
+	}
+	if size == 0 {
+		exists, err := c.client.Exists(ctx, name).Result()
+		if err != nil {
+			return nil, err
+		}
+		if exists == 0 {
+			return nil, &fs.PathError{Op: "get", Path: name, Err: fs.ErrNotExist}
+		}
+	}
+	return &redisObjectReader{ctx: ctx, client: c.client, name: name, size: size}, nil
+}
+
+// Put implements [github.com/goproxy/goproxy.Cacher], writing the value in
+// [redisCacheChunkSize] chunks via repeated APPEND rather than buffering it
+// into memory all at once.
+func (c *redisCacher) Put(ctx context.Context, name string, content io.ReadSeeker) error {
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if err := c.client.Del(ctx, name).Err(); err != nil {
+		return err
+	}
+	buf := make([]byte, redisCacheChunkSize)
+	for {
+		n, err := content.Read(buf)
+		if n > 0 {
+			if appendErr := c.client.Append(ctx, name, string(buf[:n])).Err(); appendErr != nil {
+				return appendErr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// redisObjectReader streams a Redis string value a chunk at a time using
+// GETRANGE, implementing [io.ReadCloser].
+type redisObjectReader struct {
+	ctx    context.Context
+	client *redis.Client
+	name   string
+	size   int64
+	offset int64
+}
+
+// Read implements [io.Reader].
+func (r *redisObjectReader) Read(p []byte) (int, error) {
+	if r.offset >= r.size {
+		return 0, io.EOF
+	}
+	end := r.offset + int64(len(p))
+	if end > r.size {
+		end = r.size
+	}
+	if end-r.offset > redisCacheChunkSize {
+		end = r.offset + redisCacheChunkSize
+	}
+	chunk, err := r.client.GetRange(r.ctx, r.name, r.offset, end-1).Result()
+	if err != nil {
+		return 0, err
+	}
+	n := copy(p, chunk)
+	r.offset += int64(n)
+	return n, nil
+}
+
+// Close implements [io.Closer].
+func (r *redisObjectReader) Close() error {
+	return nil
+}
@@ -0,0 +1,82 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// s3Cacher is a [github.com/goproxy/goproxy.Cacher] backed by an S3-compatible
+// object store. Objects are streamed in and out without buffering the full
+// module zip in memory.
+type s3Cacher struct {
+	client *s3.Client
+	bucket string
+}
+
+// newS3Cacher creates an [s3Cacher] for cfg.cacheS3Bucket, loading
+// credentials from the standard AWS environment variables and credential
+// chain (AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, AWS_PROFILE, etc.).
+func newS3Cacher(ctx context.Context, cfg *serverCmdConfig) (*s3Cacher, error) {
+	if cfg.cacheS3Bucket == "" {
+		return nil, fmt.Errorf("cache-s3-bucket is required for the s3 cache backend")
+	}
+	var opts []func(*config.LoadOptions) error
+	if cfg.cacheS3Region != "" {
+		opts = append(opts, config.WithRegion(cfg.cacheS3Region))
+	}
+	awsCfg, err := config.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("load AWS config: %w", err)
+	}
+	client := s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+		if cfg.cacheS3Endpoint != "" {
+			o.BaseEndpoint = aws.String(cfg.cacheS3Endpoint)
+			o.UsePathStyle = true
+		}
+	})
+	return &s3Cacher{client: client, bucket: cfg.cacheS3Bucket}, nil
+}
+
+// Get implements [github.com/goproxy/goproxy.Cacher], translating a missing
+// object into fs.ErrNotExist so callers can tell a cache miss apart from a
+// hard backend failure.
+func (c *s3Cacher) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	out, err := c.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(c.bucket),
+		Key:    aws.String(name),
+	})
+	if err != nil {
+		var nsk *types.NoSuchKey
+		if errors.As(err, &nsk) {
+			return nil, &fs.PathError{Op: "get", Path: name, Err: fs.ErrNotExist}
+		}
+		return nil, err
+	}
+	return out.Body, nil
+}
+
+// Put implements [github.com/goproxy/goproxy.Cacher].
+func (c *s3Cacher) Put(ctx context.Context, name string, content io.ReadSeeker) error {
+	size, err := content.Seek(0, io.SeekEnd)
+	if err != nil {
+		return fmt.Errorf("determine size of %s: %w", name, err)
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	_, err = c.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(c.bucket),
+		Key:           aws.String(name),
+		Body:          content,
+		ContentLength: aws.Int64(size),
+	})
+	return err
+}
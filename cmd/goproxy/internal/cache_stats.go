@@ -0,0 +1,85 @@
+package internal
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/goproxy/goproxy"
+)
+
+// cacheStats records the cache outcome (hit or miss) of the [goproxy.Cacher]
+// calls made while serving a single request.
+type cacheStats struct {
+	mu  sync.Mutex
+	hit *bool
+}
+
+// record stores whether the cache lookup was a hit, keeping the first
+// recorded outcome if multiple cache operations occur for one request.
+func (s *cacheStats) record(hit bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.hit == nil {
+		s.hit = &hit
+	}
+}
+
+// result returns "hit", "miss", or "" if no cache lookup was recorded.
+func (s *cacheStats) result() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case s.hit == nil:
+		return ""
+	case *s.hit:
+		return "hit"
+	default:
+		return "miss"
+	}
+}
+
+// cacheStatsContextKey is the context key under which a request's
+// [cacheStats] is stored.
+type cacheStatsContextKey struct{}
+
+// withCacheStats returns a copy of ctx that carries stats, for a
+// [statsCacher] to populate as it serves the request.
+func withCacheStats(ctx context.Context, stats *cacheStats) context.Context {
+	return context.WithValue(ctx, cacheStatsContextKey{}, stats)
+}
+
+// cacheStatsFromContext returns the [cacheStats] stored in ctx, if any.
+func cacheStatsFromContext(ctx context.Context) (*cacheStats, bool) {
+	stats, ok := ctx.Value(cacheStatsContextKey{}).(*cacheStats)
+	return stats, ok
+}
+
+// statsCacher wraps a [goproxy.Cacher], recording each Get's hit/miss
+// outcome into the calling request's [cacheStats] (see [withCacheStats])
+// and, if metrics is non-nil, into the process-wide Prometheus counters.
+type statsCacher struct {
+	goproxy.Cacher
+	metrics *metrics
+}
+
+// Get implements [goproxy.Cacher].
+func (c statsCacher) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	rc, err := c.Cacher.Get(ctx, name)
+	hit := err == nil
+	if stats, ok := cacheStatsFromContext(ctx); ok {
+		stats.record(hit)
+	}
+	if c.metrics != nil {
+		c.metrics.recordCacheHit(hit)
+	}
+	return rc, err
+}
+
+// Put implements [goproxy.Cacher].
+func (c statsCacher) Put(ctx context.Context, name string, content io.ReadSeeker) error {
+	if c.metrics != nil {
+		c.metrics.recordCachePut(content)
+	}
+	return c.Cacher.Put(ctx, name, content)
+}
@@ -0,0 +1,144 @@
+package internal
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// metrics holds the Prometheus instruments exposed on --metrics-address.
+type metrics struct {
+	registry *prometheus.Registry
+
+	fetchesTotal           *prometheus.CounterVec
+	fetchDurationSeconds   *prometheus.HistogramVec
+	bytesServedTotal       prometheus.Counter
+	directFetchConcurrency prometheus.Gauge
+	cacheHitsTotal         prometheus.Counter
+	cacheMissesTotal       prometheus.Counter
+	cacheBytesStoredTotal  prometheus.Counter
+}
+
+// newMetrics creates a [metrics] registered on a dedicated
+// [prometheus.Registry], kept separate from the default global registry so
+// the metrics listener only ever exposes goproxy's own instrumentation.
+func newMetrics() *metrics {
+	m := &metrics{
+		registry: prometheus.NewRegistry(),
+		fetchesTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "goproxy_fetches_total",
+			Help: "Total number of module proxy requests, by operation and HTTP status text.",
+		}, []string{"op", "status"}),
+		fetchDurationSeconds: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "goproxy_fetch_duration_seconds",
+			Help:    "Module proxy request latency in seconds, by operation, measured over the whole request including requests served from cache.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"op"}),
+		bytesServedTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "goproxy_bytes_served_total",
+			Help: "Total number of response bytes served.",
+		}),
+		directFetchConcurrency: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "goproxy_direct_fetch_concurrency",
+			Help: "Number of direct fetches currently in flight.",
+		}),
+		cacheHitsTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "goproxy_cache_hits_total",
+			Help: "Total number of cache lookups that were hits.",
+		}),
+		cacheMissesTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "goproxy_cache_misses_total",
+			Help: "Total number of cache lookups that were misses.",
+		}),
+		cacheBytesStoredTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "goproxy_cache_bytes_stored_total",
+			Help: "Total number of bytes written to the cache.",
+		}),
+	}
+	m.registry.MustRegister(
+		m.fetchesTotal,
+		m.fetchDurationSeconds,
+		m.bytesServedTotal,
+		m.directFetchConcurrency,
+		m.cacheHitsTotal,
+		m.cacheMissesTotal,
+		m.cacheBytesStoredTotal,
+	)
+	return m
+}
+
+// Handler returns the [http.Handler] that serves /metrics, /healthz, and
+// /readyz for m.
+func (m *metrics) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}))
+	mux.HandleFunc("/healthz", func(rw http.ResponseWriter, _ *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+		_, _ = rw.Write([]byte("ok"))
+	})
+	mux.HandleFunc("/readyz", func(rw http.ResponseWriter, _ *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+		_, _ = rw.Write([]byte("ok"))
+	})
+	return mux
+}
+
+// metricsMiddleware wraps next, recording per-request fetch counts,
+// whole-request latency histograms broken down by the goproxy `op` (as
+// parsed by [parseModuleRequestPath], or "sumdb" for checksum database
+// requests), and bytes served into m.
+func metricsMiddleware(next http.Handler, m *metrics) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		_, _, op, sumdb := parseModuleRequestPath(req.URL.Path)
+		if op == "" && sumdb != "" {
+			op = "sumdb"
+		}
+
+		start := time.Now()
+		lrw := &accessLogResponseWriter{ResponseWriter: rw}
+		next.ServeHTTP(lrw, req)
+
+		m.fetchDurationSeconds.WithLabelValues(op).Observe(time.Since(start).Seconds())
+		m.fetchesTotal.WithLabelValues(op, http.StatusText(lrw.status)).Inc()
+		m.bytesServedTotal.Add(float64(lrw.bytes))
+	})
+}
+
+// directFetchConcurrencyMiddleware wraps only the server's own
+// [goproxy.Goproxy] handler (the "direct" path reached when a request isn't
+// forwarded to an upstream proxy), tracking how many requests that handler
+// is serving in m.directFetchConcurrency. The count includes requests the
+// handler answers from its own Cacher without fetching. It must not wrap
+// the full request chain, since requests forwarded upstream never reach
+// the direct path at all.
+func directFetchConcurrencyMiddleware(next http.Handler, m *metrics) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		m.directFetchConcurrency.Inc()
+		defer m.directFetchConcurrency.Dec()
+		next.ServeHTTP(rw, req)
+	})
+}
+
+// recordCacheHit records a cache lookup outcome into m.
+func (m *metrics) recordCacheHit(hit bool) {
+	if hit {
+		m.cacheHitsTotal.Inc()
+	} else {
+		m.cacheMissesTotal.Inc()
+	}
+}
+
+// recordCachePut records the size of content written to the cache into m.
+func (m *metrics) recordCachePut(content io.ReadSeeker) {
+	size, err := content.Seek(0, io.SeekEnd)
+	if err != nil {
+		return
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return
+	}
+	m.cacheBytesStoredTotal.Add(float64(size))
+}
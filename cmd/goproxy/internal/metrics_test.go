@@ -0,0 +1,88 @@
+package internal
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestDirectFetchConcurrencyMiddleware(t *testing.T) {
+	m := newMetrics()
+	inFlight := make(chan struct{})
+	release := make(chan struct{})
+	next := http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+		close(inFlight)
+		<-release
+		rw.WriteHeader(http.StatusOK)
+	})
+	h := directFetchConcurrencyMiddleware(next, m)
+
+	done := make(chan struct{})
+	go func() {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/mod/@v/list", nil))
+		close(done)
+	}()
+
+	<-inFlight
+	if got := testutil.ToFloat64(m.directFetchConcurrency); got != 1 {
+		t.Errorf("directFetchConcurrency while in flight = %v, want 1", got)
+	}
+	close(release)
+	<-done
+
+	if got := testutil.ToFloat64(m.directFetchConcurrency); got != 0 {
+		t.Errorf("directFetchConcurrency after completion = %v, want 0", got)
+	}
+}
+
+func TestMetricsMiddlewareDoesNotTrackDirectFetchConcurrency(t *testing.T) {
+	m := newMetrics()
+	next := http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+		if got := testutil.ToFloat64(m.directFetchConcurrency); got != 0 {
+			t.Errorf("directFetchConcurrency while metricsMiddleware request in flight = %v, want 0", got)
+		}
+		rw.WriteHeader(http.StatusOK)
+		_, _ = rw.Write([]byte("hello"))
+	})
+	h := metricsMiddleware(next, m)
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/example.com/mod/@v/list", nil))
+
+	if got := testutil.ToFloat64(m.bytesServedTotal); got != 5 {
+		t.Errorf("bytesServedTotal = %v, want 5", got)
+	}
+	if got := testutil.ToFloat64(m.fetchesTotal.WithLabelValues("list", http.StatusText(http.StatusOK))); got != 1 {
+		t.Errorf("fetchesTotal{op=list,status=OK} = %v, want 1", got)
+	}
+}
+
+func TestRecordCacheHit(t *testing.T) {
+	m := newMetrics()
+	m.recordCacheHit(true)
+	m.recordCacheHit(false)
+	m.recordCacheHit(false)
+
+	if got := testutil.ToFloat64(m.cacheHitsTotal); got != 1 {
+		t.Errorf("cacheHitsTotal = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(m.cacheMissesTotal); got != 2 {
+		t.Errorf("cacheMissesTotal = %v, want 2", got)
+	}
+}
+
+func TestRecordCachePut(t *testing.T) {
+	m := newMetrics()
+	content := bytes.NewReader([]byte("0123456789"))
+
+	m.recordCachePut(content)
+
+	if got := testutil.ToFloat64(m.cacheBytesStoredTotal); got != 10 {
+		t.Errorf("cacheBytesStoredTotal = %v, want 10", got)
+	}
+	if pos, err := content.Seek(0, 1); err != nil || pos != 0 {
+		t.Errorf("recordCachePut left reader at offset %d, want 0", pos)
+	}
+}
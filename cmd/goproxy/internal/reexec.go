@@ -0,0 +1,88 @@
+package internal
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+)
+
+// listenFDsEnv is the environment variable that communicates to a re-exec'd
+// child how many listener file descriptors it inherited, starting at fd 3.
+const listenFDsEnv = "GOPROXY_LISTEN_FDS"
+
+// inheritedListenerFiles returns the listener files inherited from a parent
+// process via [listenFDsEnv] and [exec.Cmd.ExtraFiles], in the order they
+// were passed. It returns nil if the process was not started with inherited
+// listeners.
+func inheritedListenerFiles() []*os.File {
+	count, err := strconv.Atoi(os.Getenv(listenFDsEnv))
+	if err != nil || count <= 0 {
+		return nil
+	}
+	files := make([]*os.File, count)
+	for i := range files {
+		files[i] = os.NewFile(uintptr(3+i), fmt.Sprintf("goproxy-listener-%d", i))
+	}
+	return files
+}
+
+// listenOrInherit returns the listener at position index among the files
+// inherited from a parent process (see [inheritedListenerFiles]), or listens
+// afresh on address if there is no such inherited listener.
+func listenOrInherit(inherited []*os.File, index int, address string) (net.Listener, error) {
+	if index < len(inherited) {
+		l, err := net.FileListener(inherited[index])
+		if err != nil {
+			return nil, fmt.Errorf("use inherited listener %d: %w", index, err)
+		}
+		return l, nil
+	}
+	return net.Listen("tcp", address)
+}
+
+// reexecSelf forks a child process running the same command and arguments
+// as the current process, handing off the given listeners (in order) as
+// inherited file descriptors via [exec.Cmd.ExtraFiles] and [listenFDsEnv].
+// Listeners that are nil are skipped.
+func reexecSelf(listeners ...net.Listener) error {
+	var files []*os.File
+	for _, l := range listeners {
+		if l == nil {
+			continue
+		}
+		f, err := listenerFile(l)
+		if err != nil {
+			return err
+		}
+		files = append(files, f)
+	}
+
+	cmd := exec.Command(os.Args[0], os.Args[1:]...)
+	cmd.Env = append(os.Environ(), fmt.Sprintf("%s=%d", listenFDsEnv, len(files)))
+	cmd.ExtraFiles = files
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err := cmd.Start()
+	// The child has its own dup of each fd from fork/exec; close our copies
+	// so SIGUSR2's fork-without-shutdown doesn't leak one per invocation.
+	for _, f := range files {
+		_ = f.Close()
+	}
+	return err
+}
+
+// listenerFile duplicates l's underlying file descriptor so it can be
+// passed to a child process via [exec.Cmd.ExtraFiles].
+func listenerFile(l net.Listener) (*os.File, error) {
+	type filer interface {
+		File() (*os.File, error)
+	}
+	f, ok := l.(filer)
+	if !ok {
+		return nil, fmt.Errorf("listener of type %T does not support file descriptor handoff", l)
+	}
+	return f.File()
+}
@@ -0,0 +1,63 @@
+package internal
+
+import (
+	"net"
+	"testing"
+)
+
+func TestInheritedListenerFilesNone(t *testing.T) {
+	t.Setenv(listenFDsEnv, "")
+	if files := inheritedListenerFiles(); files != nil {
+		t.Errorf("inheritedListenerFiles() = %v, want nil", files)
+	}
+}
+
+func TestInheritedListenerFilesInvalid(t *testing.T) {
+	for _, v := range []string{"not-a-number", "-1", "0"} {
+		t.Setenv(listenFDsEnv, v)
+		if files := inheritedListenerFiles(); files != nil {
+			t.Errorf("inheritedListenerFiles() with %s=%q = %v, want nil", listenFDsEnv, v, files)
+		}
+	}
+}
+
+func TestInheritedListenerFilesCount(t *testing.T) {
+	t.Setenv(listenFDsEnv, "2")
+	files := inheritedListenerFiles()
+	if len(files) != 2 {
+		t.Fatalf("len(files) = %d, want 2", len(files))
+	}
+	for i, f := range files {
+		if f == nil {
+			t.Errorf("files[%d] is nil", i)
+		}
+	}
+}
+
+func TestListenOrInheritFresh(t *testing.T) {
+	l, err := listenOrInherit(nil, 0, "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listenOrInherit: %v", err)
+	}
+	defer l.Close()
+	if l.Addr() == nil {
+		t.Errorf("listener has no address")
+	}
+}
+
+func TestListenerFile(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen: %v", err)
+	}
+	defer l.Close()
+
+	f, err := listenerFile(l)
+	if err != nil {
+		t.Fatalf("listenerFile: %v", err)
+	}
+	defer f.Close()
+	if f.Name() == "" {
+		t.Errorf("listenerFile returned a file with no name")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/tls"
 	"errors"
+	"fmt"
 	"net"
 	"net/http"
 	"os"
@@ -15,6 +16,8 @@ import (
 
 	"github.com/goproxy/goproxy"
 	"github.com/spf13/cobra"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
 )
 
 // newServerCmd creates a new server command.
@@ -38,11 +41,33 @@ type serverCmdConfig struct {
 	address          string
 	tlsCertFile      string
 	tlsKeyFile       string
+	acme             bool
+	acmeHosts        []string
+	acmeCacheDir     string
+	acmeEmail        string
+	acmeDirectoryURL string
+	acmeHTTPAddress  string
+	tlsSNICerts      []string
+	tlsClientCAFile  string
+	accessLogFile    string
+	accessLogFormat  string
+	accessLogFields  []string
+	metricsAddress   string
+	upstreams        []string
+	upstreamDefault  string
+	upstreamNoproxy  []string
+	upstreamAuthFile string
 	pathPrefix       string
 	goBinName        string
 	maxDirectFetches int
 	proxiedSUMDBs    []string
 	cacheDir         string
+	cacheBackend     string
+	cacheS3Bucket    string
+	cacheS3Endpoint  string
+	cacheS3Region    string
+	cacheGCSBucket   string
+	cacheRedisAddr   string
 	tempDir          string
 	insecure         bool
 	connectTimeout   time.Duration
@@ -57,11 +82,33 @@ func newServerCmdConfig(cmd *cobra.Command) *serverCmdConfig {
 	fs.StringVar(&cfg.address, "address", "localhost:8080", "TCP address that the server listens on")
 	fs.StringVar(&cfg.tlsCertFile, "tls-cert-file", "", "path to the TLS certificate file")
 	fs.StringVar(&cfg.tlsKeyFile, "tls-key-file", "", "path to the TLS key file")
+	fs.BoolVar(&cfg.acme, "acme", false, "provision a TLS certificate automatically via ACME instead of using tls-cert-file/tls-key-file")
+	fs.StringSliceVar(&cfg.acmeHosts, "acme-hosts", nil, "allowlist of hosts that the ACME manager is allowed to provision certificates for")
+	fs.StringVar(&cfg.acmeCacheDir, "acme-cache-dir", "acme-cache", "directory that is used to cache ACME account and certificate data")
+	fs.StringVar(&cfg.acmeEmail, "acme-email", "", "contact email address sent to the ACME CA for expiry and issue notifications")
+	fs.StringVar(&cfg.acmeDirectoryURL, "acme-directory-url", acme.LetsEncryptURL, "ACME directory URL (set to a staging or private CA URL to avoid production rate limits)")
+	fs.StringVar(&cfg.acmeHTTPAddress, "acme-http-address", ":80", "TCP address that serves the ACME HTTP-01 challenge fallback")
+	fs.StringArrayVar(&cfg.tlsSNICerts, "tls-sni-cert", nil, "repeatable host=cert.pem,key.pem pair that serves an additional hostname (supports *.example.com wildcards) from the same listener")
+	fs.StringVar(&cfg.tlsClientCAFile, "tls-client-ca-file", "", "path to a PEM file of client CA certificates used to authenticate clients via mTLS")
+	fs.StringVar(&cfg.accessLogFile, "access-log", "", "path to the access log file (access logging is disabled if empty)")
+	fs.StringVar(&cfg.accessLogFormat, "access-log-format", accessLogFormatCombined, "format of the access log (combined, common, json)")
+	fs.StringSliceVar(&cfg.accessLogFields, "access-log-fields", nil, "structured fields to include in json access log entries (module, version, op, sumdb, cache, latency, bytes); defaults to all")
+	fs.StringVar(&cfg.metricsAddress, "metrics-address", "", "TCP address that serves Prometheus metrics at /metrics plus /healthz and /readyz (disabled if empty)")
+	fs.StringArrayVar(&cfg.upstreams, "upstream", nil, "repeatable pattern=proxy1,proxy2,... rule (pattern matched as in GOPROXY/GONOPROXY) routing modules matching pattern to an upstream proxy chain")
+	fs.StringVar(&cfg.upstreamDefault, "upstream-default", "https://proxy.golang.org,direct", "default upstream proxy chain (as in GOPROXY) for modules not matched by --upstream")
+	fs.StringSliceVar(&cfg.upstreamNoproxy, "upstream-noproxy", nil, "glob patterns (matched as in GONOPROXY/GOPRIVATE, so a bare module prefix also matches everything below it) of modules that always bypass upstream proxies and are fetched directly")
+	fs.StringVar(&cfg.upstreamAuthFile, "upstream-auth-file", "", "path to a file of <url> <Header-Name>: <value> lines providing per-upstream auth headers")
 	fs.StringVar(&cfg.pathPrefix, "path-prefix", "", "prefix for all request paths")
 	fs.StringVar(&cfg.goBinName, "go-bin-name", "go", "name of the Go binary that is used to execute direct fetches")
 	fs.IntVar(&cfg.maxDirectFetches, "max-direct-fetches", 0, "maximum number (0 means no limit) of concurrent direct fetches")
 	fs.StringSliceVar(&cfg.proxiedSUMDBs, "proxied-sumdbs", nil, "list of proxied checksum databases")
 	fs.StringVar(&cfg.cacheDir, "cache-dir", "caches", "directory that used to cache module files")
+	fs.StringVar(&cfg.cacheBackend, "cache-backend", cacheBackendDir, "cache storage backend to use (dir, s3, gcs, redis)")
+	fs.StringVar(&cfg.cacheS3Bucket, "cache-s3-bucket", "", "S3 bucket used by the s3 cache backend")
+	fs.StringVar(&cfg.cacheS3Endpoint, "cache-s3-endpoint", "", "custom S3-compatible endpoint URL used by the s3 cache backend")
+	fs.StringVar(&cfg.cacheS3Region, "cache-s3-region", "", "AWS region used by the s3 cache backend")
+	fs.StringVar(&cfg.cacheGCSBucket, "cache-gcs-bucket", "", "Google Cloud Storage bucket used by the gcs cache backend")
+	fs.StringVar(&cfg.cacheRedisAddr, "cache-redis-addr", "", "address (host:port) used by the redis cache backend")
 	fs.StringVar(&cfg.tempDir, "temp-dir", os.TempDir(), "directory for storing temporary files")
 	fs.BoolVar(&cfg.insecure, "insecure", false, "allow insecure TLS connections")
 	fs.DurationVar(&cfg.connectTimeout, "connect-timeout", 30*time.Second, "maximum amount of time (0 means no limit) will wait for an outgoing connection to establish")
@@ -70,22 +117,84 @@ func newServerCmdConfig(cmd *cobra.Command) *serverCmdConfig {
 	return cfg
 }
 
+// validateACMEConfig reports an error if cfg enables --acme without the
+// --acme-hosts allowlist that [autocert.Manager] requires to provision
+// anything: with zero hosts, [autocert.HostWhitelist] rejects every SNI
+// name, so every handshake would otherwise fail with an opaque error.
+func validateACMEConfig(cfg *serverCmdConfig) error {
+	if cfg.acme && len(cfg.acmeHosts) == 0 {
+		return fmt.Errorf("--acme requires --acme-hosts")
+	}
+	return nil
+}
+
 // runServerCmd runs the server command.
 func runServerCmd(cmd *cobra.Command, args []string, cfg *serverCmdConfig) error {
 	transport := http.DefaultTransport.(*http.Transport).Clone()
 	transport.DialContext = (&net.Dialer{Timeout: cfg.connectTimeout, KeepAlive: 30 * time.Second}).DialContext
 	transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: cfg.insecure}
 	transport.RegisterProtocol("file", http.NewFileTransport(httpDirFS{}))
+	var m *metrics
+	if cfg.metricsAddress != "" {
+		m = newMetrics()
+	}
+
+	cacher, err := newCacher(cmd.Context(), cfg)
+	if err != nil {
+		return err
+	}
+
 	g := &goproxy.Goproxy{
 		GoBinName:        cfg.goBinName,
 		MaxDirectFetches: cfg.maxDirectFetches,
 		ProxiedSUMDBs:    cfg.proxiedSUMDBs,
-		Cacher:           goproxy.DirCacher(cfg.cacheDir),
+		Cacher:           statsCacher{Cacher: cacher, metrics: m},
 		TempDir:          cfg.tempDir,
 		Transport:        transport,
 	}
 
-	handler := http.Handler(g)
+	var accessLog *accessLogger
+	if cfg.accessLogFile != "" {
+		accessLog, err = newAccessLogger(cfg.accessLogFile, cfg.accessLogFormat, cfg.accessLogFields)
+		if err != nil {
+			return err
+		}
+		defer accessLog.Close()
+	}
+
+	directHandler := http.Handler(g)
+	if m != nil {
+		directHandler = directFetchConcurrencyMiddleware(directHandler, m)
+	}
+	handler := directHandler
+	if len(cfg.upstreams) > 0 || cfg.upstreamDefault != "" {
+		rules := make([]upstreamRule, 0, len(cfg.upstreams))
+		for _, s := range cfg.upstreams {
+			rule, err := parseUpstreamRule(s)
+			if err != nil {
+				return err
+			}
+			rules = append(rules, rule)
+		}
+		authHeaders, err := loadUpstreamAuthHeaders(cfg.upstreamAuthFile)
+		if err != nil {
+			return err
+		}
+		handler = &upstreamRouter{
+			rules:          rules,
+			defaultProxies: strings.Split(cfg.upstreamDefault, ","),
+			noproxy:        cfg.upstreamNoproxy,
+			authHeaders:    authHeaders,
+			transport:      transport,
+			direct:         directHandler,
+		}
+	}
+	if accessLog != nil {
+		handler = accessLogMiddleware(handler, accessLog)
+	}
+	if m != nil {
+		handler = metricsMiddleware(handler, m)
+	}
 	if cfg.pathPrefix != "" {
 		handler = http.StripPrefix(cfg.pathPrefix, handler)
 	}
@@ -104,18 +213,168 @@ func runServerCmd(cmd *cobra.Command, args []string, cfg *serverCmdConfig) error
 		Handler:     handler,
 		BaseContext: func(_ net.Listener) context.Context { return cmd.Context() },
 	}
+
+	if err := validateACMEConfig(cfg); err != nil {
+		return err
+	}
+	var acmeManager *autocert.Manager
+	if cfg.acme {
+		acmeManager = &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			Cache:      autocert.DirCache(cfg.acmeCacheDir),
+			Email:      cfg.acmeEmail,
+			HostPolicy: autocert.HostWhitelist(cfg.acmeHosts...),
+			Client:     &acme.Client{DirectoryURL: cfg.acmeDirectoryURL},
+		}
+		server.TLSConfig = acmeManager.TLSConfig()
+	}
+
+	var certManager *tlsCertManager
+	if !cfg.acme && (cfg.tlsCertFile != "" || len(cfg.tlsSNICerts) > 0) {
+		sniCerts := make([]sniCertEntry, 0, len(cfg.tlsSNICerts))
+		for _, s := range cfg.tlsSNICerts {
+			e, err := parseSNICertEntry(s)
+			if err != nil {
+				return err
+			}
+			sniCerts = append(sniCerts, e)
+		}
+		cm, err := newTLSCertManager(cfg.tlsCertFile, cfg.tlsKeyFile, sniCerts, cfg.tlsClientCAFile)
+		if err != nil {
+			return err
+		}
+		defer cm.Close()
+		go cm.watch(nil)
+		certManager = cm
+		server.TLSConfig = &tls.Config{
+			GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+				tlsConfig := &tls.Config{GetCertificate: cm.GetCertificate}
+				if clientCAs := cm.GetClientCertificatePool(); clientCAs != nil {
+					tlsConfig.ClientCAs = clientCAs
+					tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+				}
+				return tlsConfig, nil
+			},
+		}
+	}
+
+	inherited := inheritedListenerFiles()
+	listener, err := listenOrInherit(inherited, 0, cfg.address)
+	if err != nil {
+		return err
+	}
+
+	var acmeHTTPListener net.Listener
+	if acmeManager != nil {
+		acmeHTTPListener, err = listenOrInherit(inherited, 1, cfg.acmeHTTPAddress)
+		if err != nil {
+			return err
+		}
+	}
+
 	stopCtx, stop := signal.NotifyContext(cmd.Context(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
-	var serverError error
+	// errCh collects the first fatal error reported by any of the
+	// goroutines below. It's sized to the maximum number of concurrent
+	// senders so every send is non-blocking; a shared `var serverError
+	// error` written from multiple goroutines would be a data race.
+	errCh := make(chan error, 4)
+	var acmeHTTPServer *http.Server
+	if acmeManager != nil {
+		acmeHTTPServer = &http.Server{
+			Addr:        cfg.acmeHTTPAddress,
+			Handler:     acmeManager.HTTPHandler(nil),
+			BaseContext: func(_ net.Listener) context.Context { return cmd.Context() },
+		}
+		go func() {
+			if err := acmeHTTPServer.Serve(acmeHTTPListener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				errCh <- err
+				stop()
+			}
+		}()
+	}
+
+	var metricsServer *http.Server
+	if m != nil {
+		metricsListener, err := net.Listen("tcp", cfg.metricsAddress)
+		if err != nil {
+			return err
+		}
+		metricsServer = &http.Server{
+			Addr:        cfg.metricsAddress,
+			Handler:     m.Handler(),
+			BaseContext: func(_ net.Listener) context.Context { return cmd.Context() },
+		}
+		go func() {
+			if err := metricsServer.Serve(metricsListener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				errCh <- err
+				stop()
+			}
+		}()
+	}
 	go func() {
-		if cfg.tlsCertFile != "" && cfg.tlsKeyFile != "" {
-			serverError = server.ListenAndServeTLS(cfg.tlsCertFile, cfg.tlsKeyFile)
+		var err error
+		if acmeManager != nil || certManager != nil {
+			err = server.ServeTLS(listener, "", "")
 		} else {
-			serverError = server.ListenAndServe()
+			err = server.Serve(listener)
+		}
+		if err != nil {
+			errCh <- err
 		}
 		stop()
 	}()
+
+	if accessLog != nil {
+		rotateChan := make(chan os.Signal, 1)
+		signal.Notify(rotateChan, syscall.SIGUSR1)
+		defer signal.Stop(rotateChan)
+		go func() {
+			for {
+				select {
+				case <-stopCtx.Done():
+					return
+				case _, ok := <-rotateChan:
+					if !ok {
+						return
+					}
+					_ = accessLog.Reopen()
+				}
+			}
+		}()
+	}
+
+	reexecChan := make(chan os.Signal, 1)
+	signal.Notify(reexecChan, syscall.SIGHUP, syscall.SIGUSR2)
+	defer signal.Stop(reexecChan)
+	go func() {
+		for {
+			select {
+			case <-stopCtx.Done():
+				return
+			case sig, ok := <-reexecChan:
+				if !ok {
+					return
+				}
+				if err := reexecSelf(listener, acmeHTTPListener); err != nil {
+					errCh <- fmt.Errorf("reexec on %s: %w", sig, err)
+					stop()
+					return
+				}
+				if sig == syscall.SIGHUP {
+					stop()
+					return
+				}
+			}
+		}
+	}()
+
 	<-stopCtx.Done()
+	var serverError error
+	select {
+	case serverError = <-errCh:
+	default:
+	}
 	if serverError != nil && !errors.Is(serverError, http.ErrServerClosed) {
 		return serverError
 	}
@@ -126,6 +385,12 @@ func runServerCmd(cmd *cobra.Command, args []string, cfg *serverCmdConfig) error
 		shutdownCtx, cancel = context.WithTimeout(shutdownCtx, cfg.shutdownTimeout)
 		defer cancel()
 	}
+	if acmeHTTPServer != nil {
+		_ = acmeHTTPServer.Shutdown(shutdownCtx)
+	}
+	if metricsServer != nil {
+		_ = metricsServer.Shutdown(shutdownCtx)
+	}
 	return server.Shutdown(shutdownCtx)
 }
 
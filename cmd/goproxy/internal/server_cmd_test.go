@@ -0,0 +1,23 @@
+package internal
+
+import "testing"
+
+func TestValidateACMEConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     *serverCmdConfig
+		wantErr bool
+	}{
+		{"acme disabled", &serverCmdConfig{acme: false}, false},
+		{"acme enabled with hosts", &serverCmdConfig{acme: true, acmeHosts: []string{"proxy.example.com"}}, false},
+		{"acme enabled without hosts", &serverCmdConfig{acme: true}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateACMEConfig(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateACMEConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package internal
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// tlsCertManagerPollInterval is how often the certificate files are
+// re-read even in the absence of an fsnotify event, as a fallback for file
+// systems or editors (e.g. atomic renames onto a watched path) that don't
+// reliably deliver one.
+const tlsCertManagerPollInterval = time.Minute
+
+// sniCertEntry is a parsed `--tls-sni-cert host=cert.pem,key.pem` flag value.
+type sniCertEntry struct {
+	host     string
+	certFile string
+	keyFile  string
+}
+
+// parseSNICertEntry parses a `host=cert.pem,key.pem` flag value into a
+// [sniCertEntry].
+func parseSNICertEntry(s string) (sniCertEntry, error) {
+	host, rest, ok := strings.Cut(s, "=")
+	if !ok {
+		return sniCertEntry{}, fmt.Errorf("invalid tls-sni-cert %q: missing \"=\"", s)
+	}
+	certFile, keyFile, ok := strings.Cut(rest, ",")
+	if !ok {
+		return sniCertEntry{}, fmt.Errorf("invalid tls-sni-cert %q: missing \",\"", s)
+	}
+	return sniCertEntry{host: host, certFile: certFile, keyFile: keyFile}, nil
+}
+
+// tlsCertManager serves TLS certificates that can be rotated without
+// restarting the server and dispatches to a per-host certificate based on
+// SNI, similarly to how Kubernetes' API server picks a named certificate.
+type tlsCertManager struct {
+	defaultCertFile string
+	defaultKeyFile  string
+	sniCerts        []sniCertEntry
+	clientCAFile    string
+
+	watcher *fsnotify.Watcher
+
+	mu         sync.RWMutex
+	defaultCrt *tls.Certificate
+	namedCerts map[string]*tls.Certificate
+	clientCAs  *x509.CertPool
+}
+
+// newTLSCertManager creates a new [tlsCertManager] and performs its initial
+// certificate load.
+func newTLSCertManager(defaultCertFile, defaultKeyFile string, sniCerts []sniCertEntry, clientCAFile string) (*tlsCertManager, error) {
+	m := &tlsCertManager{
+		defaultCertFile: defaultCertFile,
+		defaultKeyFile:  defaultKeyFile,
+		sniCerts:        sniCerts,
+		clientCAFile:    clientCAFile,
+	}
+	if err := m.reload(); err != nil {
+		return nil, err
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("create fsnotify watcher: %w", err)
+	}
+	for _, name := range m.watchedFiles() {
+		if err := watcher.Add(name); err != nil {
+			_ = watcher.Close()
+			return nil, fmt.Errorf("watch %s: %w", name, err)
+		}
+	}
+	m.watcher = watcher
+	return m, nil
+}
+
+// watchedFiles returns the set of files that must be watched for changes.
+func (m *tlsCertManager) watchedFiles() []string {
+	var files []string
+	if m.defaultCertFile != "" {
+		files = append(files, m.defaultCertFile, m.defaultKeyFile)
+	}
+	for _, e := range m.sniCerts {
+		files = append(files, e.certFile, e.keyFile)
+	}
+	if m.clientCAFile != "" {
+		files = append(files, m.clientCAFile)
+	}
+	return files
+}
+
+// reload reads all configured certificate, key, and client CA files and
+// atomically swaps them in.
+func (m *tlsCertManager) reload() error {
+	var defaultCrt *tls.Certificate
+	if m.defaultCertFile != "" {
+		crt, err := tls.LoadX509KeyPair(m.defaultCertFile, m.defaultKeyFile)
+		if err != nil {
+			return fmt.Errorf("load %s/%s: %w", m.defaultCertFile, m.defaultKeyFile, err)
+		}
+		defaultCrt = &crt
+	}
+
+	namedCerts := make(map[string]*tls.Certificate, len(m.sniCerts))
+	for _, e := range m.sniCerts {
+		crt, err := tls.LoadX509KeyPair(e.certFile, e.keyFile)
+		if err != nil {
+			return fmt.Errorf("load %s/%s: %w", e.certFile, e.keyFile, err)
+		}
+		namedCerts[strings.ToLower(e.host)] = &crt
+	}
+
+	var clientCAs *x509.CertPool
+	if m.clientCAFile != "" {
+		pem, err := os.ReadFile(m.clientCAFile)
+		if err != nil {
+			return fmt.Errorf("read %s: %w", m.clientCAFile, err)
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in %s", m.clientCAFile)
+		}
+	}
+
+	m.mu.Lock()
+	m.defaultCrt = defaultCrt
+	m.namedCerts = namedCerts
+	m.clientCAs = clientCAs
+	m.mu.Unlock()
+	return nil
+}
+
+// watch blocks reloading certificates whenever a watched file changes, or
+// every [tlsCertManagerPollInterval] as a fallback, until the watcher is
+// closed.
+func (m *tlsCertManager) watch(onError func(error)) {
+	ticker := time.NewTicker(tlsCertManagerPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case event, ok := <-m.watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Remove|fsnotify.Rename) == 0 {
+				continue
+			}
+			if err := m.reload(); err != nil && onError != nil {
+				onError(err)
+			}
+		case err, ok := <-m.watcher.Errors:
+			if !ok {
+				return
+			}
+			if onError != nil {
+				onError(err)
+			}
+		case <-ticker.C:
+			if err := m.reload(); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+// Close stops watching the certificate files.
+func (m *tlsCertManager) Close() error {
+	if m.watcher == nil {
+		return nil
+	}
+	return m.watcher.Close()
+}
+
+// GetCertificate implements the signature required by
+// [tls.Config.GetCertificate], dispatching to a named certificate based on
+// the client's requested SNI host, falling back to the default certificate.
+func (m *tlsCertManager) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if hello.ServerName != "" {
+		if crt := matchNamedCertificate(m.namedCerts, strings.ToLower(hello.ServerName)); crt != nil {
+			return crt, nil
+		}
+	}
+	if m.defaultCrt != nil {
+		return m.defaultCrt, nil
+	}
+	return nil, fmt.Errorf("no TLS certificate available for server name %q", hello.ServerName)
+}
+
+// GetClientCertificatePool returns the current client CA pool used to
+// authenticate clients for mTLS, or nil if client authentication is
+// disabled.
+func (m *tlsCertManager) GetClientCertificatePool() *x509.CertPool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.clientCAs
+}
+
+// matchNamedCertificate finds the certificate for host in certs, mirroring
+// Kubernetes' getNamedCertificateMap lookup: an exact match wins, otherwise
+// the host's immediate parent wildcard (`*.sub.example.com`) is tried.
+func matchNamedCertificate(certs map[string]*tls.Certificate, host string) *tls.Certificate {
+	if crt, ok := certs[host]; ok {
+		return crt
+	}
+	if i := strings.IndexByte(host, '.'); i != -1 {
+		if crt, ok := certs["*"+host[i:]]; ok {
+			return crt
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package internal
+
+import (
+	"crypto/tls"
+	"testing"
+)
+
+func TestParseSNICertEntry(t *testing.T) {
+	e, err := parseSNICertEntry("example.com=cert.pem,key.pem")
+	if err != nil {
+		t.Fatalf("parseSNICertEntry: %v", err)
+	}
+	want := sniCertEntry{host: "example.com", certFile: "cert.pem", keyFile: "key.pem"}
+	if e != want {
+		t.Errorf("parseSNICertEntry() = %+v, want %+v", e, want)
+	}
+
+	for _, s := range []string{"missing-equals", "example.com=cert.pem"} {
+		if _, err := parseSNICertEntry(s); err == nil {
+			t.Errorf("parseSNICertEntry(%q) = nil error, want error", s)
+		}
+	}
+}
+
+func TestMatchNamedCertificate(t *testing.T) {
+	exact := &tls.Certificate{}
+	wildcard := &tls.Certificate{}
+	certs := map[string]*tls.Certificate{
+		"exact.example.com": exact,
+		"*.sub.example.com": wildcard,
+	}
+
+	tests := []struct {
+		host string
+		want *tls.Certificate
+	}{
+		{"exact.example.com", exact},
+		{"foo.sub.example.com", wildcard},
+		{"sub.example.com", nil},
+		{"foo.bar.sub.example.com", nil},
+		{"unrelated.example.com", nil},
+		{"nodot", nil},
+	}
+	for _, tt := range tests {
+		if got := matchNamedCertificate(certs, tt.host); got != tt.want {
+			t.Errorf("matchNamedCertificate(%q) = %p, want %p", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestWatchedFiles(t *testing.T) {
+	m := &tlsCertManager{
+		defaultCertFile: "default.crt",
+		defaultKeyFile:  "default.key",
+		sniCerts: []sniCertEntry{
+			{host: "a.example.com", certFile: "a.crt", keyFile: "a.key"},
+		},
+		clientCAFile: "ca.pem",
+	}
+	want := []string{"default.crt", "default.key", "a.crt", "a.key", "ca.pem"}
+	got := m.watchedFiles()
+	if len(got) != len(want) {
+		t.Fatalf("watchedFiles() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("watchedFiles()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
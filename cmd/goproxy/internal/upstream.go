@@ -0,0 +1,242 @@
+package internal
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+)
+
+// upstreamRule is a parsed `--upstream pattern=proxy1,proxy2,...` flag
+// value, mirroring `GOPROXY` chaining semantics: proxies are tried in order
+// until one serves the module, falling through to the next on a 404/410
+// response, with the special values "direct" and "off" meaning "fetch it
+// ourselves" and "fail the request" respectively.
+type upstreamRule struct {
+	pattern string
+	proxies []string
+}
+
+// parseUpstreamRule parses a `pattern=proxy1,proxy2,...` flag value.
+func parseUpstreamRule(s string) (upstreamRule, error) {
+	pattern, rest, ok := strings.Cut(s, "=")
+	if !ok || pattern == "" || rest == "" {
+		return upstreamRule{}, fmt.Errorf("invalid upstream %q: want pattern=proxy1,proxy2,...", s)
+	}
+	return upstreamRule{pattern: pattern, proxies: strings.Split(rest, ",")}, nil
+}
+
+// matchModulePattern reports whether module matches pattern, replicating
+// the algorithm `golang.org/x/mod/module.MatchPrefixPatterns` uses for
+// `GOPRIVATE`/`GONOPROXY`/`GONOSUMCHECK`: pattern is matched via
+// [path.Match] against the module path truncated to the same number of
+// path elements as pattern, so a bare `corp.example.com/private` (no
+// special suffix needed) matches both that module and everything below
+// it, the same as it would in those environment variables.
+func matchModulePattern(pattern, module string) bool {
+	n := strings.Count(pattern, "/")
+	prefix := module
+	for i := 0; i < len(module); i++ {
+		if module[i] != '/' {
+			continue
+		}
+		if n == 0 {
+			prefix = module[:i]
+			break
+		}
+		n--
+	}
+	if n > 0 {
+		return false
+	}
+	ok, _ := path.Match(pattern, prefix)
+	return ok
+}
+
+// upstreamAuthHeaders maps an upstream proxy base URL to the extra headers
+// (typically Authorization) to send with requests forwarded to it, as
+// loaded by [loadUpstreamAuthHeaders].
+type upstreamAuthHeaders map[string]http.Header
+
+// loadUpstreamAuthHeaders reads a file of `<upstream-url> <Header-Name>:
+// <value>` lines (blank lines and lines starting with "#" are ignored) and
+// returns the per-upstream headers they describe. It returns nil if path is
+// empty.
+func loadUpstreamAuthHeaders(path string) (upstreamAuthHeaders, error) {
+	if path == "" {
+		return nil, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open upstream auth file %s: %w", path, err)
+	}
+	defer f.Close()
+
+	headers := upstreamAuthHeaders{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		base, rest, ok := strings.Cut(line, " ")
+		if !ok {
+			return nil, fmt.Errorf("invalid upstream auth entry %q: want <url> <Header-Name>: <value>", line)
+		}
+		name, value, ok := strings.Cut(strings.TrimSpace(rest), ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid upstream auth entry %q: want <url> <Header-Name>: <value>", line)
+		}
+		if headers[base] == nil {
+			headers[base] = http.Header{}
+		}
+		headers[base].Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read upstream auth file %s: %w", path, err)
+	}
+	return headers, nil
+}
+
+// upstreamRouter is an [http.Handler] that forwards module proxy requests
+// to a chain of upstream goproxy-compatible servers chosen per module path,
+// falling through between upstreams on a 404/410 response (and to a direct
+// fetch wherever "direct" appears in the chain) the same way the Go command
+// walks a `GOPROXY` list, answering 404 if the whole chain is exhausted
+// without "direct".
+type upstreamRouter struct {
+	rules          []upstreamRule
+	defaultProxies []string
+	noproxy        []string
+	authHeaders    upstreamAuthHeaders
+	transport      http.RoundTripper
+	direct         http.Handler
+}
+
+// proxiesFor returns the ordered proxy list to try for module, applying
+// noproxy, then the first matching --upstream rule in flag order, then the
+// default.
+func (u *upstreamRouter) proxiesFor(module string) []string {
+	for _, pattern := range u.noproxy {
+		if matchModulePattern(pattern, module) {
+			return []string{"direct"}
+		}
+	}
+	for _, rule := range u.rules {
+		if matchModulePattern(rule.pattern, module) {
+			return rule.proxies
+		}
+	}
+	return u.defaultProxies
+}
+
+// ServeHTTP implements [http.Handler].
+func (u *upstreamRouter) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	module, _, _, sumdb := parseModuleRequestPath(req.URL.Path)
+	if module == "" || sumdb != "" {
+		u.direct.ServeHTTP(rw, req)
+		return
+	}
+	for _, proxy := range u.proxiesFor(module) {
+		switch proxy {
+		case "direct":
+			u.direct.ServeHTTP(rw, req)
+			return
+		case "off":
+			http.Error(rw, "module lookup disabled by upstream routing rules", http.StatusNotFound)
+			return
+		}
+		if u.forward(rw, req, proxy) {
+			return
+		}
+	}
+	http.Error(rw, "no upstream proxy served this module", http.StatusNotFound)
+}
+
+// forward proxies req to the given upstream base URL, streaming the
+// response body through to rw without buffering it. It reports false
+// (without having written anything to rw) if the upstream could not be
+// reached, or answered with 404/410, so the caller can fall through to the
+// next upstream in the chain.
+func (u *upstreamRouter) forward(rw http.ResponseWriter, req *http.Request, base string) bool {
+	target, err := url.Parse(base)
+	if err != nil {
+		return false
+	}
+
+	outReq := req.Clone(req.Context())
+	outReq.URL.Scheme = target.Scheme
+	outReq.URL.Host = target.Host
+	outReq.URL.Path = singleJoiningSlash(target.Path, req.URL.Path)
+	outReq.URL.RawQuery = req.URL.RawQuery
+	outReq.Host = target.Host
+	outReq.RequestURI = ""
+	for name, values := range u.authHeaders[base] {
+		outReq.Header[name] = values
+	}
+
+	transport := u.transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	resp, err := transport.RoundTrip(outReq)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+		return false
+	}
+
+	connectionHeaders := map[string]bool{}
+	for _, name := range resp.Header.Values("Connection") {
+		connectionHeaders[http.CanonicalHeaderKey(strings.TrimSpace(name))] = true
+	}
+
+	respHeader := rw.Header()
+	for name, values := range resp.Header {
+		if hopByHopHeaders[name] || connectionHeaders[name] {
+			continue
+		}
+		respHeader[name] = values
+	}
+	rw.WriteHeader(resp.StatusCode)
+	_, _ = io.Copy(rw, resp.Body)
+	return true
+}
+
+// hopByHopHeaders are connection-specific headers that must not be
+// forwarded verbatim from an upstream response, mirroring the hop-by-hop
+// header list in RFC 7230 section 6.1 (the same set
+// [net/http/httputil.ReverseProxy] strips, which also strips any header
+// named in the response's own Connection header).
+var hopByHopHeaders = map[string]bool{
+	"Connection":          true,
+	"Proxy-Connection":    true,
+	"Keep-Alive":          true,
+	"Proxy-Authenticate":  true,
+	"Proxy-Authorization": true,
+	"Te":                  true,
+	"Trailer":             true,
+	"Transfer-Encoding":   true,
+	"Upgrade":             true,
+}
+
+// singleJoiningSlash joins a and b with exactly one slash between them,
+// mirroring the helper of the same name in [net/http/httputil.ReverseProxy].
+func singleJoiningSlash(a, b string) string {
+	aSlash := strings.HasSuffix(a, "/")
+	bSlash := strings.HasPrefix(b, "/")
+	switch {
+	case aSlash && bSlash:
+		return a + b[1:]
+	case !aSlash && !bSlash:
+		return a + "/" + b
+	default:
+		return a + b
+	}
+}
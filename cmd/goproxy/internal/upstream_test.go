@@ -0,0 +1,135 @@
+package internal
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMatchModulePattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		module  string
+		want    bool
+	}{
+		{"example.com/foo", "example.com/foo", true},
+		{"example.com/foo", "example.com/bar", false},
+		// A bare module prefix matches itself and everything below it,
+		// mirroring GOPRIVATE/GONOPROXY - no "/*" suffix required.
+		{"corp.example.com/private", "corp.example.com/private", true},
+		{"corp.example.com/private", "corp.example.com/private/sub/pkg", true},
+		{"corp.example.com/private", "corp.example.com/privatexyz", false},
+		{"example.com/foo/*", "example.com/foo/bar", true},
+		{"example.com/foo/*", "example.com/foobar", false},
+		{"example.com/*", "other.com/foo", false},
+		{"example.com/f?o", "example.com/foo", true},
+		{"example.com/f?o", "example.com/fooo", false},
+	}
+	for _, tt := range tests {
+		if got := matchModulePattern(tt.pattern, tt.module); got != tt.want {
+			t.Errorf("matchModulePattern(%q, %q) = %v, want %v", tt.pattern, tt.module, got, tt.want)
+		}
+	}
+}
+
+func TestParseUpstreamRule(t *testing.T) {
+	rule, err := parseUpstreamRule("example.com/*=https://a.example,direct")
+	if err != nil {
+		t.Fatalf("parseUpstreamRule: %v", err)
+	}
+	if rule.pattern != "example.com/*" {
+		t.Errorf("pattern = %q, want %q", rule.pattern, "example.com/*")
+	}
+	if want := []string{"https://a.example", "direct"}; !equalStrings(rule.proxies, want) {
+		t.Errorf("proxies = %v, want %v", rule.proxies, want)
+	}
+
+	for _, s := range []string{"no-equals-sign", "=noproxy", "pattern="} {
+		if _, err := parseUpstreamRule(s); err == nil {
+			t.Errorf("parseUpstreamRule(%q) = nil error, want error", s)
+		}
+	}
+}
+
+func TestSingleJoiningSlash(t *testing.T) {
+	tests := []struct {
+		a, b, want string
+	}{
+		{"https://example.com", "/mod/@v/list", "https://example.com/mod/@v/list"},
+		{"https://example.com/", "/mod/@v/list", "https://example.com/mod/@v/list"},
+		{"https://example.com/", "mod/@v/list", "https://example.com/mod/@v/list"},
+		{"https://example.com", "mod/@v/list", "https://example.com/mod/@v/list"},
+	}
+	for _, tt := range tests {
+		if got := singleJoiningSlash(tt.a, tt.b); got != tt.want {
+			t.Errorf("singleJoiningSlash(%q, %q) = %q, want %q", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestUpstreamRouterFallsThroughOn404(t *testing.T) {
+	miss := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		http.Error(rw, "not found", http.StatusNotFound)
+	}))
+	defer miss.Close()
+	hit := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		_, _ = rw.Write([]byte("v1.0.0\n"))
+	}))
+	defer hit.Close()
+
+	u := &upstreamRouter{
+		defaultProxies: []string{miss.URL, hit.URL},
+		direct: http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			http.Error(rw, "should not reach direct", http.StatusInternalServerError)
+		}),
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/example.com/mod/@v/list", nil)
+	rw := httptest.NewRecorder()
+	u.ServeHTTP(rw, req)
+
+	if rw.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rw.Code, http.StatusOK)
+	}
+	body, err := io.ReadAll(rw.Result().Body)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if string(body) != "v1.0.0\n" {
+		t.Errorf("body = %q, want %q", body, "v1.0.0\n")
+	}
+}
+
+func TestUpstreamRouterNoproxyGoesDirect(t *testing.T) {
+	var directHit bool
+	u := &upstreamRouter{
+		noproxy:        []string{"private.example.com/*"},
+		defaultProxies: []string{"https://should-not-be-used.example"},
+		direct: http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			directHit = true
+			rw.WriteHeader(http.StatusOK)
+		}),
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/private.example.com/mod/mod/@v/list", nil)
+	rw := httptest.NewRecorder()
+	u.ServeHTTP(rw, req)
+
+	if !directHit {
+		t.Errorf("request for a noproxy module was not served directly")
+	}
+}
+
+// equalStrings reports whether a and b contain the same strings in the same order.
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}